@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// countAnnotator annotates a subtree with its item count.
+type countAnnotator struct{}
+
+func (countAnnotator) Zero() int { return 0 }
+
+func (countAnnotator) Accumulate(key, value []byte, dst int) (int, bool) {
+	return dst + 1, true
+}
+
+func (countAnnotator) Merge(a, b int) int { return a + b }
+
+// TestAnnotationInvalidatedBySplitAndMerge checks that Annotation stays
+// correct across node splits (insertChildAt) and the merges fillChildAt
+// performs during delete (removeChildAt), both of which mutate a node's
+// children without going through insertItemAt/removeItemAt.
+func TestAnnotationInvalidatedBySplitAndMerge(t *testing.T) {
+	tr := NewBTree()
+	var ann countAnnotator
+
+	const n = 200
+	for i := 0; i < n; i++ {
+		key := []byte(fmt.Sprintf("k%04d", i))
+		tr.insert(key, key)
+
+		if got := Annotation[[]byte, []byte, int](tr.g, ann); got != i+1 {
+			t.Fatalf("after %d inserts, Annotation = %d, want %d", i+1, got, i+1)
+		}
+	}
+
+	for i := 0; i < n; i += 2 {
+		key := []byte(fmt.Sprintf("k%04d", i))
+		if err := tr.Delete(key); err != nil {
+			t.Fatalf("Delete(%s): %v", key, err)
+		}
+	}
+
+	want := n / 2
+	if got := Annotation[[]byte, []byte, int](tr.g, ann); got != want {
+		t.Fatalf("after deletes, Annotation = %d, want %d", got, want)
+	}
+}
+
+// sumAnnotator annotates a subtree with the sum of its int values.
+type sumAnnotator struct{}
+
+func (sumAnnotator) Zero() int { return 0 }
+
+func (sumAnnotator) Accumulate(key int, value int, dst int) (int, bool) {
+	return dst + value, true
+}
+
+func (sumAnnotator) Merge(a, b int) int { return a + b }
+
+// TestAnnotationOnIntKeyedTree checks that Annotator/Annotation work over a
+// BTreeG instantiated with non-[]byte key and value types, not just the
+// []byte wrapper BTree uses.
+func TestAnnotationOnIntKeyedTree(t *testing.T) {
+	tr := NewBTreeG[int, int](func(a, b int) bool { return a < b })
+	var ann sumAnnotator
+
+	const n = 200
+	want := 0
+	for i := 0; i < n; i++ {
+		tr.insert(i, i)
+		want += i
+
+		if got := Annotation[int, int, int](tr, ann); got != want {
+			t.Fatalf("after %d inserts, Annotation = %d, want %d", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func seedTree(t *testing.T, n int) (*BTree, [][]byte) {
+	t.Helper()
+	tr := NewBTree()
+	keys := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		keys[i] = []byte(fmt.Sprintf("k%04d", i))
+		tr.insert(keys[i], keys[i])
+	}
+	return tr, keys
+}
+
+// TestCursorFirstPrevIsExhausted checks that stepping backward from the
+// smallest key correctly reports there's nothing before it, rather than
+// climbing into an ancestor frame left over from the descent to First.
+func TestCursorFirstPrevIsExhausted(t *testing.T) {
+	tr, _ := seedTree(t, 300)
+	c := tr.Cursor()
+
+	if !c.First() {
+		t.Fatal("First() = false on a non-empty tree")
+	}
+	if c.Prev() {
+		t.Fatalf("Prev() after First() = true, landed on %s, want false", c.Key())
+	}
+}
+
+// TestCursorLastNextIsExhausted is the mirror image, stepping forward from
+// the largest key.
+func TestCursorLastNextIsExhausted(t *testing.T) {
+	tr, _ := seedTree(t, 300)
+	c := tr.Cursor()
+
+	if !c.Last() {
+		t.Fatal("Last() = false on a non-empty tree")
+	}
+	if c.Next() {
+		t.Fatalf("Next() after Last() = true, landed on %s, want false", c.Key())
+	}
+}
+
+// TestCursorFullForwardAndBackwardWalk walks the whole tree in both
+// directions via Next/Prev and checks every key is visited in order.
+func TestCursorFullForwardAndBackwardWalk(t *testing.T) {
+	tr, keys := seedTree(t, 300)
+
+	c := tr.Cursor()
+	var forward [][]byte
+	for ok := c.First(); ok; ok = c.Next() {
+		forward = append(forward, append([]byte(nil), c.Key()...))
+	}
+	if len(forward) != len(keys) {
+		t.Fatalf("forward walk visited %d items, want %d", len(forward), len(keys))
+	}
+	for i, k := range forward {
+		if !bytes.Equal(k, keys[i]) {
+			t.Fatalf("forward[%d] = %s, want %s", i, k, keys[i])
+		}
+	}
+
+	var backward [][]byte
+	for ok := c.Last(); ok; ok = c.Prev() {
+		backward = append(backward, append([]byte(nil), c.Key()...))
+	}
+	if len(backward) != len(keys) {
+		t.Fatalf("backward walk visited %d items, want %d", len(backward), len(keys))
+	}
+	for i, k := range backward {
+		if !bytes.Equal(k, keys[len(keys)-1-i]) {
+			t.Fatalf("backward[%d] = %s, want %s", i, k, keys[len(keys)-1-i])
+		}
+	}
+}
+
+// TestDescendRandomPivots checks Descend against a reference sort for
+// exact matches on the minimum and maximum key, values below the minimum
+// and above the maximum, and random pivots that don't land exactly on a key.
+func TestDescendRandomPivots(t *testing.T) {
+	tr, keys := seedTree(t, 300)
+
+	sorted := append([][]byte(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+
+	r := rand.New(rand.NewSource(1))
+	pivots := [][]byte{
+		[]byte("k0000"),       // exact match on the minimum
+		[]byte("k0299"),       // exact match on the maximum
+		[]byte("a"),           // below every key
+		[]byte("zzzzzzzzzzz"), // above every key
+	}
+	for i := 0; i < 50; i++ {
+		pivots = append(pivots, []byte(fmt.Sprintf("k%04d%s", r.Intn(300), string(rune('a'+r.Intn(5))))))
+	}
+
+	for _, pivot := range pivots {
+		var want [][]byte
+		for i := len(sorted) - 1; i >= 0; i-- {
+			if bytes.Compare(sorted[i], pivot) <= 0 {
+				want = append(want, sorted[i])
+			}
+		}
+
+		var got [][]byte
+		tr.Descend(pivot, func(key, value []byte) bool {
+			got = append(got, append([]byte(nil), key...))
+			return true
+		})
+
+		if len(got) != len(want) {
+			t.Fatalf("Descend(%s): got %d items, want %d", pivot, len(got), len(want))
+		}
+		for i := range want {
+			if !bytes.Equal(got[i], want[i]) {
+				t.Fatalf("Descend(%s)[%d] = %s, want %s", pivot, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+// TestAscendRandomPivots is Descend's mirror, covering Ascend with the same
+// boundary and random non-matching pivots.
+func TestAscendRandomPivots(t *testing.T) {
+	tr, keys := seedTree(t, 300)
+
+	sorted := append([][]byte(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+
+	r := rand.New(rand.NewSource(2))
+	pivots := [][]byte{[]byte("k0000"), []byte("k0299"), []byte("a"), []byte("zzzzzzzzzzz")}
+	for i := 0; i < 50; i++ {
+		pivots = append(pivots, []byte(fmt.Sprintf("k%04d%s", r.Intn(300), string(rune('a'+r.Intn(5))))))
+	}
+
+	for _, pivot := range pivots {
+		var want [][]byte
+		for _, k := range sorted {
+			if bytes.Compare(k, pivot) >= 0 {
+				want = append(want, k)
+			}
+		}
+
+		var got [][]byte
+		tr.Ascend(pivot, func(key, value []byte) bool {
+			got = append(got, append([]byte(nil), key...))
+			return true
+		})
+
+		if len(got) != len(want) {
+			t.Fatalf("Ascend(%s): got %d items, want %d", pivot, len(got), len(want))
+		}
+		for i := range want {
+			if !bytes.Equal(got[i], want[i]) {
+				t.Fatalf("Ascend(%s)[%d] = %s, want %s", pivot, i, got[i], want[i])
+			}
+		}
+	}
+}
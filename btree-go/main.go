@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sync/atomic"
 )
 
 const (
@@ -13,40 +14,101 @@ const (
 	minItems    = degree - 1
 )
 
-type item struct {
-	key   []byte
-	value []byte
+// item is a single key/value pair stored in a node.
+type item[K, V any] struct {
+	key   K
+	value V
 }
 
-type node struct {
-	items       [maxItems]*item
-	children    [maxChildren]*node
+type node[K, V any] struct {
+	items       [maxItems]*item[K, V]
+	children    [maxChildren]*node[K, V]
 	numItems    int
 	numChildren int
+	cow         uint64
+	annotations []annotation
 }
 
-type BTree struct {
-	root *node
+// annotation caches the result of one Annotator's Accumulate/Merge pass over
+// a node's subtree. ann holds the Annotator that produced value, compared by
+// identity, so a node can serve several independent annotators at once.
+type annotation struct {
+	ann   any
+	value any
+	valid bool
+}
+
+// invalidateAnnotations discards n's cached annotation values. Called from
+// every mutation that changes n's items or children, since a stale cache
+// would otherwise be returned by a later Annotation call.
+func (n *node[K, V]) invalidateAnnotations() {
+	for i := range n.annotations {
+		n.annotations[i].valid = false
+	}
+}
+
+// setAnnotation stores value as a's cached result for n's subtree.
+func (n *node[K, V]) setAnnotation(a any, value any) {
+	for i := range n.annotations {
+		if n.annotations[i].ann == a {
+			n.annotations[i].value = value
+			n.annotations[i].valid = true
+			return
+		}
+	}
+	n.annotations = append(n.annotations, annotation{ann: a, value: value, valid: true})
+}
+
+// cowSeq hands out the unique cow ids used to tell nodes owned by distinct
+// snapshots apart. Allocating through atomic.AddUint64 means concurrent
+// Clone calls never hand out the same id.
+var cowSeq uint64
+
+func nextCow() uint64 {
+	return atomic.AddUint64(&cowSeq, 1)
 }
 
-func (n *node) isLeaf() bool {
+// cowClone returns n if it is already owned by cow, otherwise a shallow copy
+// of n tagged with cow. The copy shares item and child pointers with n until
+// those are themselves mutated, which is what makes Clone an O(1) snapshot.
+func (n *node[K, V]) cowClone(cow uint64) *node[K, V] {
+	if n == nil || n.cow == cow {
+		return n
+	}
+	cp := *n
+	cp.cow = cow
+	if len(n.annotations) > 0 {
+		// Give the clone its own backing array so invalidating its cache
+		// can't reach back and invalidate n's.
+		cp.annotations = append([]annotation(nil), n.annotations...)
+	}
+	return &cp
+}
+
+// BTreeG is a B-tree keyed by K and ordered by a user-supplied less
+// function. cow tags every node this tree currently owns; see Clone.
+type BTreeG[K, V any] struct {
+	root *node[K, V]
+	less func(a, b K) bool
+	cow  uint64
+}
+
+func (n *node[K, V]) isLeaf() bool {
 	return n.numChildren == 0
 }
 
-func (n *node) search(key []byte) (int, bool) {
+func (n *node[K, V]) search(less func(a, b K) bool, key K) (int, bool) {
 	low, high := 0, n.numItems
 	var mid int
 
 	for low < high {
 		mid = (low + high) / 2
-		cmp := bytes.Compare(key, n.items[mid].key)
-
 		switch {
-		case cmp > 0:
+		case less(key, n.items[mid].key):
+			high = mid
+		case less(n.items[mid].key, key):
 			low = mid + 1
-		case cmp < 0:
-			high = cmp
-		case cmp == 0:
+		default:
 			return mid, true
 		}
 	}
@@ -54,29 +116,31 @@ func (n *node) search(key []byte) (int, bool) {
 	return low, false
 }
 
-func (n *node) insertItemAt(pos int, i *item) {
+func (n *node[K, V]) insertItemAt(pos int, i *item[K, V]) {
 	if pos < n.numItems {
 		copy(n.items[pos+1:n.numItems+1], n.items[pos:n.numItems])
 	}
 
 	n.items[pos] = i
 	n.numItems++
+	n.invalidateAnnotations()
 }
 
-func (n *node) insertChildAt(pos int, c *node) {
-	if pos < n.numItems {
-		copy(n.items[pos+1:n.numItems+1], n.items[pos:n.numItems])
+func (n *node[K, V]) insertChildAt(pos int, c *node[K, V]) {
+	if pos < n.numChildren {
+		copy(n.children[pos+1:n.numChildren+1], n.children[pos:n.numChildren])
 	}
 
 	n.children[pos] = c
-	n.numItems++
+	n.numChildren++
+	n.invalidateAnnotations()
 }
 
-func (n *node) split() (*item, *node) {
+func (n *node[K, V]) split() (*item[K, V], *node[K, V]) {
 	mid := minItems
 	midItem := n.items[mid]
 
-	newNode := &node{}
+	newNode := &node[K, V]{}
 	copy(newNode.items[:], n.items[mid+1:])
 	newNode.numItems = minItems
 
@@ -95,48 +159,66 @@ func (n *node) split() (*item, *node) {
 		}
 	}
 
+	n.invalidateAnnotations()
+
 	return midItem, newNode
 }
 
-func (n *node) insert(item *item) error {
-    pos, found := n.search(item.key)
-    if found {
-        n.items[pos] = item
-        return nil
-    }
-
-    if n.isLeaf() {
-        n.insertItemAt(pos, item)
-        return nil
-    }
-
-    // Extract the split handling into a separate method
-    if err := n.handleNodeSplit(pos, item); err != nil {
-        return err
-    }
-
-    return n.children[pos].insert(item)
-}
-
-func (n *node) handleNodeSplit(pos int, item *item) error {
-    if n.children[pos].numItems >= maxItems {
-        midItem, newNode := n.children[pos].split()
-        n.insertItemAt(pos, midItem)
-        n.insertChildAt(pos+1, newNode)
-        
-        // Adjust position based on comparison
-        switch cmp := bytes.Compare(item.key, n.items[pos].key); {
-        case cmp > 0:
-            pos++
-        case cmp == 0:
-            n.items[pos] = item
-            return nil
-        }
-    }
-    return nil
-}
-
-func (n *node) removeItemAt(pos int) *item {
+func (n *node[K, V]) insert(less func(a, b K) bool, cow uint64, i *item[K, V]) error {
+	pos, found := n.search(less, i.key)
+	if found {
+		n.items[pos] = i
+		n.invalidateAnnotations()
+		return nil
+	}
+
+	if n.isLeaf() {
+		n.insertItemAt(pos, i)
+		return nil
+	}
+
+	pos, done, err := n.handleNodeSplit(less, cow, pos, i)
+	if err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+
+	child := n.children[pos].cowClone(cow)
+	n.children[pos] = child
+	err = child.insert(less, cow, i)
+	n.invalidateAnnotations()
+	return err
+}
+
+// handleNodeSplit splits the child at pos if it's full, then returns the
+// child index i now belongs under. Since the split may shift i's target
+// child to pos+1, or land i on the item that bubbled up into n itself (in
+// which case it returns done=true and the caller has nothing left to do),
+// the caller must use the returned pos rather than the one it passed in.
+func (n *node[K, V]) handleNodeSplit(less func(a, b K) bool, cow uint64, pos int, i *item[K, V]) (int, bool, error) {
+	if n.children[pos].numItems >= maxItems {
+		child := n.children[pos].cowClone(cow)
+		n.children[pos] = child
+
+		midItem, newNode := child.split()
+		newNode.cow = cow
+		n.insertItemAt(pos, midItem)
+		n.insertChildAt(pos+1, newNode)
+
+		switch {
+		case less(n.items[pos].key, i.key):
+			pos++
+		case !less(i.key, n.items[pos].key):
+			n.items[pos] = i
+			return pos, true, nil
+		}
+	}
+	return pos, false, nil
+}
+
+func (n *node[K, V]) removeItemAt(pos int) *item[K, V] {
 	removedItem := n.items[pos]
 	n.items[pos] = nil
 
@@ -145,11 +227,12 @@ func (n *node) removeItemAt(pos int) *item {
 		n.items[lastPos] = nil
 	}
 	n.numItems--
+	n.invalidateAnnotations()
 
 	return removedItem
 }
 
-func (n *node) removeChildAt(pos int) *node {
+func (n *node[K, V]) removeChildAt(pos int) *node[K, V] {
 	removedChild := n.children[pos]
 	n.children[pos] = nil
 
@@ -158,14 +241,17 @@ func (n *node) removeChildAt(pos int) *node {
 		n.children[lastPos] = nil
 	}
 	n.numChildren--
+	n.invalidateAnnotations()
 
 	return removedChild
 }
 
-func (n *node) fillChildAt(pos int) {
+func (n *node[K, V]) fillChildAt(cow uint64, pos int) {
 	switch {
 	case pos > 0 && n.children[pos-1].numItems > minItems:
-		left, right := n.children[pos-1], n.children[pos]
+		left := n.children[pos-1].cowClone(cow)
+		right := n.children[pos].cowClone(cow)
+		n.children[pos-1], n.children[pos] = left, right
 		copy(right.items[1:right.numItems+1], right.items[:right.numItems])
 		right.items[0] = n.items[pos-1]
 		right.numItems++
@@ -173,19 +259,27 @@ func (n *node) fillChildAt(pos int) {
 			right.insertChildAt(0, left.removeChildAt(left.numChildren-1))
 		}
 		n.items[pos-1] = left.removeItemAt(left.numItems - 1)
+		n.invalidateAnnotations()
+		right.invalidateAnnotations()
 	case pos < n.numChildren-1 && n.children[pos+1].numItems > minItems:
-		left, right := n.children[pos], n.children[pos+1]
+		left := n.children[pos].cowClone(cow)
+		right := n.children[pos+1].cowClone(cow)
+		n.children[pos], n.children[pos+1] = left, right
 		left.items[left.numItems] = n.items[pos]
 		left.numItems++
 		if !left.isLeaf() {
 			left.insertChildAt(left.numChildren, right.removeChildAt(0))
 		}
 		n.items[pos] = right.removeItemAt(0)
+		n.invalidateAnnotations()
+		left.invalidateAnnotations()
 	default:
 		if pos >= n.numItems {
 			pos = n.numItems - 1
 		}
-		left, right := n.children[pos], n.children[pos+1]
+		left := n.children[pos].cowClone(cow)
+		right := n.children[pos+1]
+		n.children[pos] = left
 		left.items[left.numItems] = n.removeItemAt(pos)
 		left.numItems++
 		copy(left.items[left.numItems:], right.items[:right.numItems])
@@ -196,13 +290,15 @@ func (n *node) fillChildAt(pos int) {
 		}
 		n.removeChildAt(pos + 1)
 		right = nil
+		left.invalidateAnnotations()
 	}
 }
 
-func (n *node) delete(key []byte, isSeekingSuccessor bool) *item {
-	pos, found := n.search(key)
+func (n *node[K, V]) delete(less func(a, b K) bool, cow uint64, key K, isSeekingSuccessor bool) *item[K, V] {
+	pos, found := n.search(less, key)
 
-	var next *node
+	var childIdx int
+	var next *node[K, V]
 
 	// We have found a node holding an item matching the supplied key.
 	if found {
@@ -211,9 +307,11 @@ func (n *node) delete(key []byte, isSeekingSuccessor bool) *item {
 			return n.removeItemAt(pos)
 		}
 		// This is not a leaf node, so we have to find the inorder successor.
-		next, isSeekingSuccessor = n.children[pos+1], true
+		childIdx, isSeekingSuccessor = pos+1, true
+		next = n.children[childIdx]
 	} else {
-		next = n.children[pos]
+		childIdx = pos
+		next = n.children[childIdx]
 	}
 
 	// We have reached the leaf node containing the inorder successor, so remove the successor from the leaf.
@@ -226,8 +324,12 @@ func (n *node) delete(key []byte, isSeekingSuccessor bool) *item {
 		return nil
 	}
 
+	// We're about to mutate next, so make sure we own it first.
+	next = next.cowClone(cow)
+	n.children[childIdx] = next
+
 	// Continue traversing the tree to find an item matching the supplied key.
-	deletedItem := next.delete(key, isSeekingSuccessor)
+	deletedItem := next.delete(less, cow, key, isSeekingSuccessor)
 
 	// We found the inorder successor, and we are now back at the internal node containing the item
 	// matching the supplied key. Therefore, we replace the item with its inorder successor, effectively
@@ -240,27 +342,42 @@ func (n *node) delete(key []byte, isSeekingSuccessor bool) *item {
 	if next.numItems < minItems {
 		// Repair the underflow.
 		if found && isSeekingSuccessor {
-			n.fillChildAt(pos + 1)
+			n.fillChildAt(cow, pos+1)
 		} else {
-			n.fillChildAt(pos)
+			n.fillChildAt(cow, pos)
 		}
 	}
 
+	// A descendant changed even if n's own items/children didn't, so n's
+	// cached annotations (which fold in every descendant's aggregate) are
+	// now stale regardless of whether fillChildAt ran.
+	n.invalidateAnnotations()
+
 	// Propagate the deleted item back to the previous stack frame.
 	return deletedItem
 }
 
-func NewBTree() *BTree {
-	return &BTree{}
+// NewBTreeG creates an empty B-tree keyed by K, ordered by less.
+func NewBTreeG[K, V any](less func(a, b K) bool) *BTreeG[K, V] {
+	return &BTreeG[K, V]{less: less, cow: nextCow()}
 }
 
-func (t *BTree) Find(key []byte) ([]byte, error) {
+// Clone returns an O(1) snapshot of t that initially shares its entire
+// structure with t. Both t and the returned tree are given fresh cow ids, so
+// any subsequent mutation on either side copies the touched path instead of
+// disturbing the other tree's view.
+func (t *BTreeG[K, V]) Clone() *BTreeG[K, V] {
+	t.cow = nextCow()
+	return &BTreeG[K, V]{root: t.root, less: t.less, cow: nextCow()}
+}
+
+func (t *BTreeG[K, V]) Find(key K) (V, error) {
 	/*
 	* The Find function navigates through the B-tree by updating the next
 	* pointer to the appropriate child node based on comparisons with the keys in the current node.
 	 */
 	for next := t.root; next != nil; {
-		pos, found := next.search(key)
+		pos, found := next.search(t.less, key)
 		if found {
 			return next.items[pos].value, nil
 		}
@@ -268,69 +385,429 @@ func (t *BTree) Find(key []byte) ([]byte, error) {
 		next = next.children[pos]
 	}
 
-	return nil, errors.New("key not found")
+	var zero V
+	return zero, errors.New("key not found")
 }
 
-func (t *BTree) splitRoot() {
-	newRoot := &node{}
+func (t *BTreeG[K, V]) splitRoot() {
+	newRoot := &node[K, V]{cow: t.cow}
 	midItem, newNode := t.root.split()
+	newNode.cow = t.cow
 	newRoot.insertItemAt(0, midItem)
 	newRoot.insertChildAt(0, t.root)
 	newRoot.insertChildAt(1, newNode)
 	t.root = newRoot
 }
 
-func (t *BTree) insert(key, val []byte) {
-	i := &item{key, val}
+func (t *BTreeG[K, V]) insert(key K, val V) {
+	i := &item[K, V]{key, val}
 
 	if t.root == nil {
-		t.root = &node{}
+		t.root = &node[K, V]{cow: t.cow}
+	} else {
+		t.root = t.root.cowClone(t.cow)
 	}
 
 	if t.root.numItems >= maxItems {
 		t.splitRoot()
 	}
 
-	t.root.insert(i)
+	t.root.insert(t.less, t.cow, i)
+}
+
+func (t *BTreeG[K, V]) Delete(key K) error {
+	if t.root == nil {
+		return errors.New("tree is empty")
+	}
+	t.root = t.root.cowClone(t.cow)
+
+	deletedItem := t.root.delete(t.less, t.cow, key, false)
+	if deletedItem == nil {
+		return errors.New("key not found")
+	}
+
+	if t.root.numItems == 0 {
+		if t.root.isLeaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+
+	return nil
+}
+
+// frame is one level of a Cursor's path stack: the node being visited and
+// the index of the item at that level which is current (already returned,
+// if the frame belongs to a leaf) or pending (if the frame belongs to an
+// ancestor waiting for a child subtree to finish).
+type frame[K, V any] struct {
+	n   *node[K, V]
+	pos int
+}
+
+// Cursor supports ordered traversal of a BTreeG via Seek/First/Last/Next/Prev.
+// It holds an explicit stack of (node, index) frames rather than recursing,
+// so repeated Next/Prev calls are O(1) amortized and O(log n) worst case,
+// paying the descent cost only when a subtree is exhausted.
+//
+// A Cursor reads live nodes, not a snapshot: if the tree is mutated while a
+// Cursor is positioned on it, the cursor may skip, repeat, or miss items
+// near the mutation, and Seek/First/Last must be called again to get a
+// consistent view. It will not panic or corrupt the tree. Take a Clone of
+// the tree first if a stable view during mutation is required.
+type Cursor[K, V any] struct {
+	t     *BTreeG[K, V]
+	stack []frame[K, V]
+}
+
+// Cursor returns a new, unpositioned Cursor over t. Call First, Last, or
+// Seek before reading Key/Value.
+func (t *BTreeG[K, V]) Cursor() *Cursor[K, V] {
+	return &Cursor[K, V]{t: t}
+}
+
+func (c *Cursor[K, V]) pushLeftmost(n *node[K, V]) {
+	for n != nil {
+		c.stack = append(c.stack, frame[K, V]{n: n, pos: 0})
+		if n.isLeaf() {
+			break
+		}
+		n = n.children[0]
+	}
+}
+
+func (c *Cursor[K, V]) pushRightmost(n *node[K, V]) {
+	for n != nil {
+		if n.isLeaf() {
+			c.stack = append(c.stack, frame[K, V]{n: n, pos: n.numItems - 1})
+			return
+		}
+		// An internal frame's pos is the index of the child it descended
+		// into, same as pushLeftmost and Seek use for the frames above a
+		// found item. Storing numItems-1 here (the rightmost item) instead
+		// of numChildren-1 (the rightmost child) would make Prev's climb
+		// think that item was already the predecessor, skipping it.
+		c.stack = append(c.stack, frame[K, V]{n: n, pos: n.numChildren - 1})
+		n = n.children[n.numChildren-1]
+	}
+}
+
+// valid reports whether the top-of-stack frame currently names a real item.
+func (c *Cursor[K, V]) valid() bool {
+	if len(c.stack) == 0 {
+		return false
+	}
+	top := c.stack[len(c.stack)-1]
+	return top.pos >= 0 && top.pos < top.n.numItems
+}
+
+// First positions the cursor on the smallest key in the tree.
+func (c *Cursor[K, V]) First() bool {
+	c.stack = c.stack[:0]
+	if c.t.root != nil {
+		c.pushLeftmost(c.t.root)
+	}
+	return c.valid()
+}
+
+// Last positions the cursor on the largest key in the tree.
+func (c *Cursor[K, V]) Last() bool {
+	c.stack = c.stack[:0]
+	if c.t.root != nil {
+		c.pushRightmost(c.t.root)
+	}
+	return c.valid()
+}
+
+// Seek positions the cursor on the smallest key >= key, returning false if
+// no such key exists.
+func (c *Cursor[K, V]) Seek(key K) bool {
+	c.stack = c.stack[:0]
+
+	for n := c.t.root; n != nil; {
+		pos, found := n.search(c.t.less, key)
+		c.stack = append(c.stack, frame[K, V]{n: n, pos: pos})
+		if found || n.isLeaf() {
+			break
+		}
+		n = n.children[pos]
+	}
+
+	// Climb to the nearest ancestor holding a pending item: either we
+	// landed exactly on one above, or the final leaf had nothing >= key.
+	for len(c.stack) > 0 {
+		top := &c.stack[len(c.stack)-1]
+		if top.pos < top.n.numItems {
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
+
+// Next advances the cursor to the next larger key, returning false once the
+// end of the tree is reached.
+func (c *Cursor[K, V]) Next() bool {
+	if !c.valid() {
+		return false
+	}
+	top := &c.stack[len(c.stack)-1]
+
+	if !top.n.isLeaf() {
+		child := top.n.children[top.pos+1]
+		top.pos++
+		c.pushLeftmost(child)
+		return c.valid()
+	}
+
+	if top.pos+1 < top.n.numItems {
+		top.pos++
+		return true
+	}
+
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		top := &c.stack[len(c.stack)-1]
+		if top.pos < top.n.numItems {
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
+
+// Prev retreats the cursor to the next smaller key, returning false once
+// the start of the tree is reached.
+func (c *Cursor[K, V]) Prev() bool {
+	if !c.valid() {
+		return false
+	}
+	top := &c.stack[len(c.stack)-1]
+
+	if !top.n.isLeaf() {
+		// top.pos is already the index of the child we're about to descend
+		// into (the same "child descended into" convention pushLeftmost,
+		// pushRightmost and Seek use), so it's left unchanged here: the
+		// climb loop below is what advances it to pos-1 once this subtree
+		// is exhausted and we pop back up to top.
+		child := top.n.children[top.pos]
+		c.pushRightmost(child)
+		return c.valid()
+	}
+
+	if top.pos > 0 {
+		top.pos--
+		return true
+	}
+
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		top := &c.stack[len(c.stack)-1]
+		// An ancestor's pos is the child index we descended into, so the
+		// predecessor (if any) is the item just before that child, at
+		// pos-1. pos == 0 means the child we came from was the leftmost,
+		// so there's nothing to its left at this level either.
+		// pos-1 must also still name an item: a mutation since the
+		// descent may have shrunk this node.
+		if top.pos > 0 && top.pos <= top.n.numItems {
+			top.pos--
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
+
+// Key returns the key at the cursor's current position. It panics if the
+// cursor is not positioned on an item.
+func (c *Cursor[K, V]) Key() K {
+	top := c.stack[len(c.stack)-1]
+	return top.n.items[top.pos].key
+}
+
+// Value returns the value at the cursor's current position. It panics if
+// the cursor is not positioned on an item.
+func (c *Cursor[K, V]) Value() V {
+	top := c.stack[len(c.stack)-1]
+	return top.n.items[top.pos].value
+}
+
+// Ascend calls iter for every item with key >= pivot, in ascending order,
+// until iter returns false or the tree is exhausted.
+func (t *BTreeG[K, V]) Ascend(pivot K, iter func(key K, value V) bool) {
+	c := t.Cursor()
+	for ok := c.Seek(pivot); ok; ok = c.Next() {
+		if !iter(c.Key(), c.Value()) {
+			return
+		}
+	}
+}
+
+// Descend calls iter for every item with key <= pivot, in descending order,
+// until iter returns false or the tree is exhausted.
+func (t *BTreeG[K, V]) Descend(pivot K, iter func(key K, value V) bool) {
+	c := t.Cursor()
+	ok := c.Seek(pivot)
+	switch {
+	case !ok:
+		ok = c.Last()
+	case t.less(pivot, c.Key()):
+		ok = c.Prev()
+	}
+	for ; ok; ok = c.Prev() {
+		if !iter(c.Key(), c.Value()) {
+			return
+		}
+	}
+}
+
+// AscendRange calls iter for every item with lo <= key < hi, in ascending
+// order, until iter returns false or the range is exhausted.
+func (t *BTreeG[K, V]) AscendRange(lo, hi K, iter func(key K, value V) bool) {
+	c := t.Cursor()
+	for ok := c.Seek(lo); ok && t.less(c.Key(), hi); ok = c.Next() {
+		if !iter(c.Key(), c.Value()) {
+			return
+		}
+	}
+}
+
+func (n *node[K, V]) validate(less func(a, b K) bool) error {
+	if n.numItems > maxItems {
+		return fmt.Errorf("node contains too many items: %d", n.numItems)
+	}
+
+	if !n.isLeaf() && n.numItems+1 != n.numChildren {
+		return fmt.Errorf("invalid number of children: %d for %d items", n.numChildren, n.numItems)
+	}
+
+	for i := 0; i < n.numItems-1; i++ {
+		if !less(n.items[i].key, n.items[i+1].key) {
+			return fmt.Errorf("keys not in order at index %d", i)
+		}
+	}
+
+	return nil
+}
+
+// BTree is a []byte-keyed B-tree, kept as a thin wrapper over BTreeG so
+// existing callers don't need to deal with generics directly.
+type BTree struct {
+	g *BTreeG[[]byte, []byte]
+}
+
+func bytesLess(a, b []byte) bool {
+	return bytes.Compare(a, b) < 0
+}
+
+func NewBTree() *BTree {
+	return &BTree{g: NewBTreeG[[]byte, []byte](bytesLess)}
+}
+
+func (t *BTree) Find(key []byte) ([]byte, error) {
+	return t.g.Find(key)
+}
+
+func (t *BTree) insert(key, val []byte) {
+	t.g.insert(key, val)
 }
 
 func (t *BTree) Delete(key []byte) error {
-    if t.root == nil {
-        return errors.New("tree is empty")
-    }
-    
-    deletedItem := t.root.delete(key, false)
-    if deletedItem == nil {
-        return errors.New("key not found")
-    }
-    
-    if t.root.numItems == 0 {
-        if t.root.isLeaf() {
-            t.root = nil
-        } else {
-            t.root = t.root.children[0]
-        }
-    }
-    
-    return nil
-}
-
-func (n *node) validate() error {
-    if n.numItems > maxItems {
-        return fmt.Errorf("node contains too many items: %d", n.numItems)
-    }
-    
-    if !n.isLeaf() && n.numItems+1 != n.numChildren {
-        return fmt.Errorf("invalid number of children: %d for %d items", n.numChildren, n.numItems)
-    }
-    
-    for i := 0; i < n.numItems-1; i++ {
-        if bytes.Compare(n.items[i].key, n.items[i+1].key) >= 0 {
-            return fmt.Errorf("keys not in order at index %d", i)
-        }
-    }
-    
-    return nil
+	return t.g.Delete(key)
+}
+
+// Clone returns an O(1) snapshot of t sharing structure with t until either
+// tree is mutated.
+func (t *BTree) Clone() *BTree {
+	return &BTree{g: t.g.Clone()}
+}
+
+// Cursor returns a new, unpositioned Cursor over t.
+func (t *BTree) Cursor() *Cursor[[]byte, []byte] {
+	return t.g.Cursor()
+}
+
+// Ascend calls iter for every item with key >= pivot, in ascending order.
+func (t *BTree) Ascend(pivot []byte, iter func(key, value []byte) bool) {
+	t.g.Ascend(pivot, iter)
+}
+
+// Descend calls iter for every item with key <= pivot, in descending order.
+func (t *BTree) Descend(pivot []byte, iter func(key, value []byte) bool) {
+	t.g.Descend(pivot, iter)
+}
+
+// AscendRange calls iter for every item with lo <= key < hi, in ascending
+// order.
+func (t *BTree) AscendRange(lo, hi []byte, iter func(key, value []byte) bool) {
+	t.g.AscendRange(lo, hi, iter)
+}
+
+// Annotator computes a cacheable aggregate of type T over the K/V items of
+// a BTreeG, such as a running sum, a min/max, or a count matching some
+// predicate. Implementations must be comparable: a node tells its cached
+// annotations apart by Annotator identity, so Annotator values are
+// typically empty structs or pointers.
+type Annotator[K, V, T any] interface {
+	// Zero returns the aggregate of an empty subtree.
+	Zero() T
+	// Accumulate folds one item into dst, returning the updated aggregate
+	// and whether that result is stable enough to cache. Accumulate calls
+	// that return stable=false must not be memoized, since a later item in
+	// the same subtree could still change the answer.
+	Accumulate(key K, value V, dst T) (result T, stable bool)
+	// Merge combines the aggregates of two disjoint subtrees.
+	Merge(a, b T) T
+}
+
+// Annotation computes a's aggregate over every item in t, recomputing only
+// the subtrees whose cached value was invalidated by a mutation since the
+// last call. Go doesn't allow type parameters on methods, so this is a
+// function rather than a (*BTreeG[K, V]) method.
+func Annotation[K, V, T any](t *BTreeG[K, V], a Annotator[K, V, T]) T {
+	result, _ := annotateNode(t.root, a)
+	return result
+}
+
+// annotateNode returns a's aggregate over n's subtree and whether every
+// Accumulate call folded into it was stable. Only stable results are cached.
+func annotateNode[K, V, T any](n *node[K, V], a Annotator[K, V, T]) (T, bool) {
+	if n == nil {
+		return a.Zero(), true
+	}
+
+	for i := range n.annotations {
+		if n.annotations[i].valid && n.annotations[i].ann == a {
+			if cached, ok := n.annotations[i].value.(T); ok {
+				return cached, true
+			}
+		}
+	}
+
+	result := a.Zero()
+	stable := true
+	for i := 0; i < n.numItems; i++ {
+		if !n.isLeaf() {
+			child, childStable := annotateNode(n.children[i], a)
+			result = a.Merge(result, child)
+			stable = stable && childStable
+		}
+		var itemStable bool
+		result, itemStable = a.Accumulate(n.items[i].key, n.items[i].value, result)
+		stable = stable && itemStable
+	}
+	if !n.isLeaf() {
+		child, childStable := annotateNode(n.children[n.numItems], a)
+		result = a.Merge(result, child)
+		stable = stable && childStable
+	}
+
+	if stable {
+		n.setAnnotation(a, result)
+	}
+
+	return result, stable
 }
 
 func main() {
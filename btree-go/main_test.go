@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// walk recursively checks n and its subtree against the structural
+// invariants validate only checks one level deep: item counts, child
+// counts, and key ordering must also hold across node boundaries.
+func walk[K, V any](t *testing.T, n *node[K, V], less func(a, b K) bool, isRoot bool) {
+	t.Helper()
+
+	if err := n.validate(less); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !isRoot && n.numItems < minItems {
+		t.Fatalf("non-root node underflowed: %d items", n.numItems)
+	}
+
+	if n.isLeaf() {
+		return
+	}
+	for i := 0; i <= n.numItems; i++ {
+		child := n.children[i]
+		if child == nil {
+			t.Fatalf("child %d is nil on non-leaf node with %d items", i, n.numItems)
+		}
+		if i > 0 && !less(n.items[i-1].key, child.items[0].key) {
+			t.Fatalf("child %d out of order relative to parent item %d", i, i-1)
+		}
+		walk(t, child, less, false)
+	}
+}
+
+// TestInsertSequentialSplitsTree forces several root splits by inserting
+// enough sequential keys, then walks the whole tree to make sure
+// insertChildAt kept children and items in step. A prior version of
+// insertChildAt wrote into the items array instead of children, which left
+// numChildren at 0 and silently dropped every child past the first split.
+func TestInsertSequentialSplitsTree(t *testing.T) {
+	tr := NewBTree()
+
+	const n = 200
+	for i := 0; i < n; i++ {
+		key := []byte(fmt.Sprintf("k%04d", i))
+		tr.insert(key, key)
+	}
+
+	walk(t, tr.g.root, tr.g.less, true)
+
+	for i := 0; i < n; i++ {
+		key := []byte(fmt.Sprintf("k%04d", i))
+		val, err := tr.Find(key)
+		if err != nil {
+			t.Fatalf("Find(%s): %v", key, err)
+		}
+		if string(val) != string(key) {
+			t.Fatalf("Find(%s) = %s, want %s", key, val, key)
+		}
+	}
+}
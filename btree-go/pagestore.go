@@ -0,0 +1,698 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// pageSize is the fixed size of every page handed out by a PageStore. Keys
+// and values are assumed to comfortably fit a handful of items per page;
+// unlike a production store, PersistentBTree does not track serialized size
+// and split ahead of an overflow, it only counts items like the in-memory
+// tree does.
+const (
+	pageSize         = 4096
+	pageMagic        = 0x42545048 // "BTPH"
+	superblockPageID = 0
+)
+
+// PageStore persists fixed-size pages keyed by id. A PersistentBTree stores
+// every node as a page and loads pages on demand through this interface,
+// rather than keeping the whole tree resident as Go pointers.
+type PageStore interface {
+	ReadPage(id uint64) ([]byte, error)
+	WritePage(id uint64, data []byte) error
+	AllocPage() (uint64, error)
+	FreePage(id uint64) error
+}
+
+// FilePageStore is a PageStore backed by a single file of fixed pageSize
+// pages. Page 0 is reserved for the superblock, which is why page id 0 can
+// double as the "no node" sentinel elsewhere in this file.
+type FilePageStore struct {
+	f        *os.File
+	nextPage uint64
+	free     []uint64
+}
+
+// OpenFilePageStore opens (creating if necessary) a file-backed PageStore
+// at path.
+func OpenFilePageStore(path string) (*FilePageStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open page store: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	s := &FilePageStore{f: f}
+	if info.Size() == 0 {
+		s.nextPage = 1
+		if err := s.WritePage(superblockPageID, make([]byte, pageSize)); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+
+	s.nextPage = uint64(info.Size() / pageSize)
+	return s, nil
+}
+
+func (s *FilePageStore) ReadPage(id uint64) ([]byte, error) {
+	buf := make([]byte, pageSize)
+	if _, err := s.f.ReadAt(buf, int64(id)*pageSize); err != nil {
+		return nil, fmt.Errorf("read page %d: %w", id, err)
+	}
+	return buf, nil
+}
+
+func (s *FilePageStore) WritePage(id uint64, data []byte) error {
+	if len(data) > pageSize {
+		return fmt.Errorf("write page %d: data exceeds page size", id)
+	}
+	buf := make([]byte, pageSize)
+	copy(buf, data)
+	if _, err := s.f.WriteAt(buf, int64(id)*pageSize); err != nil {
+		return fmt.Errorf("write page %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *FilePageStore) AllocPage() (uint64, error) {
+	if n := len(s.free); n > 0 {
+		id := s.free[n-1]
+		s.free = s.free[:n-1]
+		return id, nil
+	}
+	id := s.nextPage
+	s.nextPage++
+	return id, nil
+}
+
+func (s *FilePageStore) FreePage(id uint64) error {
+	s.free = append(s.free, id)
+	return nil
+}
+
+// Close closes the underlying file.
+func (s *FilePageStore) Close() error {
+	return s.f.Close()
+}
+
+// diskNode is a node's in-memory, decoded form: the on-disk counterpart of
+// node[K, V], except children are page ids rather than pointers.
+type diskNode struct {
+	id       uint64
+	leaf     bool
+	keys     [][]byte
+	values   [][]byte
+	children []uint64 // len(keys)+1 entries when !leaf
+}
+
+// encodedDiskNodeSize returns the number of bytes encodeDiskNode would need
+// for n: the header, the child-id table, the key/value length offset table,
+// and the raw key/value bytes themselves.
+func encodedDiskNodeSize(n *diskNode) int {
+	size := 7
+	if !n.leaf {
+		size += len(n.children) * 8
+	}
+	size += len(n.keys) * 4
+	for i, k := range n.keys {
+		size += len(k) + len(n.values[i])
+	}
+	return size
+}
+
+// encodeDiskNode serializes n into a pageSize buffer: a header (magic, leaf
+// flag, item count), the child-id table, a key/value length offset table,
+// then the raw key/value bytes themselves. It errors rather than truncating
+// if n doesn't fit in a page; maxItems keeps this from happening in
+// practice (see the pageSize comment), but a node carrying unusually large
+// keys or values can still overflow one.
+func encodeDiskNode(n *diskNode) ([]byte, error) {
+	if size := encodedDiskNodeSize(n); size > pageSize {
+		return nil, fmt.Errorf("encode page %d: %d bytes needed, page size is %d", n.id, size, pageSize)
+	}
+
+	buf := make([]byte, pageSize)
+	binary.BigEndian.PutUint32(buf[0:4], pageMagic)
+	if n.leaf {
+		buf[4] = 1
+	}
+	binary.BigEndian.PutUint16(buf[5:7], uint16(len(n.keys)))
+
+	off := 7
+	if !n.leaf {
+		for _, c := range n.children {
+			binary.BigEndian.PutUint64(buf[off:off+8], c)
+			off += 8
+		}
+	}
+
+	offsetTable := off
+	off += len(n.keys) * 4
+	for i, k := range n.keys {
+		v := n.values[i]
+		binary.BigEndian.PutUint16(buf[offsetTable+i*4:], uint16(len(k)))
+		binary.BigEndian.PutUint16(buf[offsetTable+i*4+2:], uint16(len(v)))
+		off += copy(buf[off:], k)
+		off += copy(buf[off:], v)
+	}
+
+	return buf, nil
+}
+
+func decodeDiskNode(id uint64, buf []byte) (*diskNode, error) {
+	if len(buf) < 7 || binary.BigEndian.Uint32(buf[0:4]) != pageMagic {
+		return nil, fmt.Errorf("page %d: bad magic", id)
+	}
+
+	n := &diskNode{id: id, leaf: buf[4] == 1}
+	numItems := int(binary.BigEndian.Uint16(buf[5:7]))
+
+	off := 7
+	if !n.leaf {
+		n.children = make([]uint64, numItems+1)
+		for i := range n.children {
+			n.children[i] = binary.BigEndian.Uint64(buf[off : off+8])
+			off += 8
+		}
+	}
+
+	offsetTable := off
+	off += numItems * 4
+	n.keys = make([][]byte, numItems)
+	n.values = make([][]byte, numItems)
+	for i := 0; i < numItems; i++ {
+		kLen := int(binary.BigEndian.Uint16(buf[offsetTable+i*4:]))
+		vLen := int(binary.BigEndian.Uint16(buf[offsetTable+i*4+2:]))
+		n.keys[i] = append([]byte(nil), buf[off:off+kLen]...)
+		off += kLen
+		n.values[i] = append([]byte(nil), buf[off:off+vLen]...)
+		off += vLen
+	}
+
+	return n, nil
+}
+
+// pageCache is a small fixed-capacity LRU cache of decoded nodes sitting in
+// front of a PageStore, so a hot path doesn't round-trip through ReadPage on
+// every descent.
+type pageCache struct {
+	store    PageStore
+	capacity int
+	order    []uint64 // least-recently-used at the front
+	nodes    map[uint64]*diskNode
+}
+
+func newPageCache(store PageStore, capacity int) *pageCache {
+	return &pageCache{store: store, capacity: capacity, nodes: make(map[uint64]*diskNode)}
+}
+
+func (c *pageCache) get(id uint64) (*diskNode, error) {
+	if n, ok := c.nodes[id]; ok {
+		c.touch(id)
+		return n, nil
+	}
+
+	data, err := c.store.ReadPage(id)
+	if err != nil {
+		return nil, err
+	}
+	n, err := decodeDiskNode(id, data)
+	if err != nil {
+		return nil, err
+	}
+	c.put(n)
+	return n, nil
+}
+
+func (c *pageCache) put(n *diskNode) {
+	if _, ok := c.nodes[n.id]; !ok && len(c.nodes) >= c.capacity && len(c.order) > 0 {
+		evict := c.order[0]
+		c.order = c.order[1:]
+		delete(c.nodes, evict)
+	}
+	c.nodes[n.id] = n
+	c.touch(n.id)
+}
+
+func (c *pageCache) touch(id uint64) {
+	for i, v := range c.order {
+		if v == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, id)
+}
+
+func (c *pageCache) invalidate(id uint64) {
+	delete(c.nodes, id)
+	for i, v := range c.order {
+		if v == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// flush writes n's current in-memory contents to the store and keeps the
+// cache's copy up to date.
+func (c *pageCache) flush(n *diskNode) error {
+	buf, err := encodeDiskNode(n)
+	if err != nil {
+		return err
+	}
+	c.put(n)
+	return c.store.WritePage(n.id, buf)
+}
+
+func diskSearch(n *diskNode, key []byte) (int, bool) {
+	low, high := 0, len(n.keys)
+	for low < high {
+		mid := (low + high) / 2
+		switch bytes.Compare(key, n.keys[mid]) {
+		case 0:
+			return mid, true
+		case -1:
+			high = mid
+		default:
+			low = mid + 1
+		}
+	}
+	return low, false
+}
+
+// PersistentBTree is a []byte-keyed B-tree whose nodes live in a PageStore
+// instead of as in-process pointers. It deliberately doesn't reuse
+// node[K, V]: a disk node's children are page ids that must be encoded and
+// decoded through a fixed-size page, not the arbitrary K/V Go values
+// node[K, V] holds, and BTreeG has no hook for a caller to intercept node
+// layout or make a child lookup fallible (ReadPage can fail; node.children
+// indexing cannot). It shares degree, maxItems, and minItems with BTree so
+// the two trees split and merge at the same fan-out, which keeps a page's
+// on-disk layout predictable.
+type PersistentBTree struct {
+	store PageStore
+	cache *pageCache
+	root  uint64 // page id of the root node; 0 ("no node") means an empty tree
+}
+
+// NewPersistentBTree opens a PersistentBTree over store, reading its root
+// page id from the store's superblock.
+func NewPersistentBTree(store PageStore) (*PersistentBTree, error) {
+	sb, err := store.ReadPage(superblockPageID)
+	if err != nil {
+		return nil, err
+	}
+	return &PersistentBTree{
+		store: store,
+		cache: newPageCache(store, 256),
+		root:  binary.BigEndian.Uint64(sb[:8]),
+	}, nil
+}
+
+func (t *PersistentBTree) writeSuperblock() error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, t.root)
+	return t.store.WritePage(superblockPageID, buf)
+}
+
+func (t *PersistentBTree) Find(key []byte) ([]byte, error) {
+	id := t.root
+	for id != 0 {
+		n, err := t.cache.get(id)
+		if err != nil {
+			return nil, err
+		}
+		pos, found := diskSearch(n, key)
+		if found {
+			return n.values[pos], nil
+		}
+		if n.leaf {
+			break
+		}
+		id = n.children[pos]
+	}
+	return nil, errors.New("key not found")
+}
+
+func (t *PersistentBTree) Insert(key, val []byte) error {
+	if t.root == 0 {
+		id, err := t.store.AllocPage()
+		if err != nil {
+			return err
+		}
+		root := &diskNode{id: id, leaf: true}
+		if err := t.cache.flush(root); err != nil {
+			return err
+		}
+		t.root = id
+	}
+
+	root, err := t.cache.get(t.root)
+	if err != nil {
+		return err
+	}
+
+	if len(root.keys) >= maxItems {
+		root, err = t.splitRoot(root)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := t.insertNode(root, key, val); err != nil {
+		return err
+	}
+	return t.writeSuperblock()
+}
+
+func (t *PersistentBTree) splitRoot(root *diskNode) (*diskNode, error) {
+	midKey, midVal, right, err := t.splitDiskNode(root)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := t.store.AllocPage()
+	if err != nil {
+		return nil, err
+	}
+	newRoot := &diskNode{
+		id:       id,
+		keys:     [][]byte{midKey},
+		values:   [][]byte{midVal},
+		children: []uint64{root.id, right.id},
+	}
+
+	if err := t.cache.flush(root); err != nil {
+		return nil, err
+	}
+	if err := t.cache.flush(right); err != nil {
+		return nil, err
+	}
+	if err := t.cache.flush(newRoot); err != nil {
+		return nil, err
+	}
+
+	t.root = newRoot.id
+	return newRoot, nil
+}
+
+func (t *PersistentBTree) splitDiskNode(n *diskNode) (midKey, midVal []byte, right *diskNode, err error) {
+	mid := minItems
+	midKey, midVal = n.keys[mid], n.values[mid]
+
+	id, err := t.store.AllocPage()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	right = &diskNode{id: id, leaf: n.leaf}
+	right.keys = append([][]byte(nil), n.keys[mid+1:]...)
+	right.values = append([][]byte(nil), n.values[mid+1:]...)
+	if !n.leaf {
+		right.children = append([]uint64(nil), n.children[mid+1:]...)
+	}
+
+	n.keys = n.keys[:mid]
+	n.values = n.values[:mid]
+	if !n.leaf {
+		n.children = n.children[:mid+1]
+	}
+
+	return midKey, midVal, right, nil
+}
+
+func (t *PersistentBTree) insertNode(n *diskNode, key, val []byte) error {
+	pos, found := diskSearch(n, key)
+	if found {
+		n.values[pos] = val
+		return t.cache.flush(n)
+	}
+
+	if n.leaf {
+		n.keys = append(n.keys, nil)
+		n.values = append(n.values, nil)
+		copy(n.keys[pos+1:], n.keys[pos:])
+		copy(n.values[pos+1:], n.values[pos:])
+		n.keys[pos], n.values[pos] = key, val
+		return t.cache.flush(n)
+	}
+
+	child, err := t.cache.get(n.children[pos])
+	if err != nil {
+		return err
+	}
+
+	if len(child.keys) >= maxItems {
+		midKey, midVal, right, err := t.splitDiskNode(child)
+		if err != nil {
+			return err
+		}
+
+		n.keys = append(n.keys, nil)
+		n.values = append(n.values, nil)
+		copy(n.keys[pos+1:], n.keys[pos:])
+		copy(n.values[pos+1:], n.values[pos:])
+		n.keys[pos], n.values[pos] = midKey, midVal
+
+		n.children = append(n.children, 0)
+		copy(n.children[pos+2:], n.children[pos+1:])
+		n.children[pos+1] = right.id
+
+		if err := t.cache.flush(child); err != nil {
+			return err
+		}
+		if err := t.cache.flush(right); err != nil {
+			return err
+		}
+
+		switch bytes.Compare(key, n.keys[pos]) {
+		case 0:
+			n.values[pos] = val
+			return t.cache.flush(n)
+		case 1:
+			pos++
+		}
+
+		child, err = t.cache.get(n.children[pos])
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := t.insertNode(child, key, val); err != nil {
+		return err
+	}
+	return t.cache.flush(n)
+}
+
+func (t *PersistentBTree) Delete(key []byte) error {
+	if t.root == 0 {
+		return errors.New("tree is empty")
+	}
+
+	root, err := t.cache.get(t.root)
+	if err != nil {
+		return err
+	}
+
+	dKey, _, err := t.deleteNode(root, key, false)
+	if err != nil {
+		return err
+	}
+	if dKey == nil {
+		return errors.New("key not found")
+	}
+
+	root, err = t.cache.get(t.root)
+	if err != nil {
+		return err
+	}
+	if len(root.keys) == 0 {
+		if root.leaf {
+			t.root = 0
+		} else {
+			t.root = root.children[0]
+		}
+	}
+
+	return t.writeSuperblock()
+}
+
+// deleteNode mirrors node.delete, threading an inorder-successor search down
+// through page ids instead of pointers and flushing every touched node back
+// to the store before returning.
+func (t *PersistentBTree) deleteNode(n *diskNode, key []byte, isSeekingSuccessor bool) (dKey, dVal []byte, err error) {
+	pos, found := diskSearch(n, key)
+
+	var childIdx int
+	haveNext := false
+
+	if found {
+		if n.leaf {
+			dKey, dVal = n.keys[pos], n.values[pos]
+			removeItemAt(n, pos)
+			return dKey, dVal, t.cache.flush(n)
+		}
+		childIdx, isSeekingSuccessor = pos+1, true
+		haveNext = true
+	} else {
+		childIdx = pos
+		haveNext = childIdx < len(n.children)
+	}
+
+	if n.leaf && isSeekingSuccessor {
+		dKey, dVal = n.keys[0], n.values[0]
+		removeItemAt(n, 0)
+		return dKey, dVal, t.cache.flush(n)
+	}
+
+	if !haveNext {
+		return nil, nil, nil
+	}
+
+	next, err := t.cache.get(n.children[childIdx])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dKey, dVal, err = t.deleteNode(next, key, isSeekingSuccessor)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dKey == nil {
+		return nil, nil, nil
+	}
+
+	if found && isSeekingSuccessor {
+		n.keys[pos], n.values[pos] = dKey, dVal
+	}
+
+	if len(next.keys) < minItems {
+		if found && isSeekingSuccessor {
+			if err := t.fillChildAt(n, pos+1); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			if err := t.fillChildAt(n, pos); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if err := t.cache.flush(n); err != nil {
+		return nil, nil, err
+	}
+	return dKey, dVal, nil
+}
+
+func removeItemAt(n *diskNode, pos int) {
+	n.keys = append(n.keys[:pos], n.keys[pos+1:]...)
+	n.values = append(n.values[:pos], n.values[pos+1:]...)
+}
+
+// fillChildAt repairs an underflowing child of n at pos, mirroring
+// node.fillChildAt: borrow a key from a sibling that can spare one, or
+// failing that, merge the child with a sibling.
+func (t *PersistentBTree) fillChildAt(n *diskNode, pos int) error {
+	if pos > 0 {
+		left, err := t.cache.get(n.children[pos-1])
+		if err != nil {
+			return err
+		}
+		if len(left.keys) > minItems {
+			right, err := t.cache.get(n.children[pos])
+			if err != nil {
+				return err
+			}
+			right.keys = append([][]byte{n.keys[pos-1]}, right.keys...)
+			right.values = append([][]byte{n.values[pos-1]}, right.values...)
+			if !right.leaf {
+				lastChild := left.children[len(left.children)-1]
+				left.children = left.children[:len(left.children)-1]
+				right.children = append([]uint64{lastChild}, right.children...)
+			}
+			n.keys[pos-1] = left.keys[len(left.keys)-1]
+			n.values[pos-1] = left.values[len(left.values)-1]
+			left.keys = left.keys[:len(left.keys)-1]
+			left.values = left.values[:len(left.values)-1]
+			return t.flushAll(left, right, n)
+		}
+	}
+
+	if pos < len(n.children)-1 {
+		right, err := t.cache.get(n.children[pos+1])
+		if err != nil {
+			return err
+		}
+		if len(right.keys) > minItems {
+			left, err := t.cache.get(n.children[pos])
+			if err != nil {
+				return err
+			}
+			left.keys = append(left.keys, n.keys[pos])
+			left.values = append(left.values, n.values[pos])
+			if !left.leaf {
+				left.children = append(left.children, right.children[0])
+				right.children = right.children[1:]
+			}
+			n.keys[pos] = right.keys[0]
+			n.values[pos] = right.values[0]
+			right.keys = right.keys[1:]
+			right.values = right.values[1:]
+			return t.flushAll(left, right, n)
+		}
+	}
+
+	// Neither sibling has a key to spare: merge pos and pos+1 into pos.
+	if pos >= len(n.keys) {
+		pos = len(n.keys) - 1
+	}
+	left, err := t.cache.get(n.children[pos])
+	if err != nil {
+		return err
+	}
+	right, err := t.cache.get(n.children[pos+1])
+	if err != nil {
+		return err
+	}
+
+	left.keys = append(left.keys, n.keys[pos])
+	left.values = append(left.values, n.values[pos])
+	left.keys = append(left.keys, right.keys...)
+	left.values = append(left.values, right.values...)
+	if !left.leaf {
+		left.children = append(left.children, right.children...)
+	}
+
+	n.keys = append(n.keys[:pos], n.keys[pos+1:]...)
+	n.values = append(n.values[:pos], n.values[pos+1:]...)
+	n.children = append(n.children[:pos+1], n.children[pos+2:]...)
+
+	if err := t.store.FreePage(right.id); err != nil {
+		return err
+	}
+	t.cache.invalidate(right.id)
+
+	return t.flushAll(left, n)
+}
+
+func (t *PersistentBTree) flushAll(nodes ...*diskNode) error {
+	for _, n := range nodes {
+		if err := t.cache.flush(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEncodeDiskNodeOversizeReturnsError checks that a node whose keys and
+// values don't fit in one page is rejected by encodeDiskNode instead of
+// being silently truncated by the copy into a fixed pageSize buffer.
+func TestEncodeDiskNodeOversizeReturnsError(t *testing.T) {
+	n := &diskNode{id: 1, leaf: true}
+	for i := 0; i < 8; i++ {
+		n.keys = append(n.keys, []byte(fmt.Sprintf("k%04d", i)))
+		n.values = append(n.values, []byte(strings.Repeat("v", 600)))
+	}
+
+	if _, err := encodeDiskNode(n); err == nil {
+		t.Fatal("encodeDiskNode with oversize values = nil error, want an error")
+	}
+}
+
+// TestPersistentBTreeInsertOversizeValueReturnsError reproduces the
+// overflow end to end: inserting enough large values into one leaf should
+// surface an error from Insert, not corrupt the page.
+func TestPersistentBTreeInsertOversizeValueReturnsError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.db")
+	store, err := OpenFilePageStore(path)
+	if err != nil {
+		t.Fatalf("OpenFilePageStore: %v", err)
+	}
+	defer store.Close()
+
+	tr, err := NewPersistentBTree(store)
+	if err != nil {
+		t.Fatalf("NewPersistentBTree: %v", err)
+	}
+
+	var insertErr error
+	for i := 0; i < 8; i++ {
+		key := []byte(fmt.Sprintf("k%04d", i))
+		val := []byte(strings.Repeat("v", 600))
+		if insertErr = tr.Insert(key, val); insertErr != nil {
+			break
+		}
+	}
+	if insertErr == nil {
+		t.Fatal("Insert of oversize values = nil error, want an error once the leaf overflows a page")
+	}
+}
+
+// TestFilePageStoreReopenRoundTrip checks that a tree survives closing and
+// reopening its backing file, the ordinary use of a PersistentBTree.
+func TestFilePageStoreReopenRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.db")
+
+	store, err := OpenFilePageStore(path)
+	if err != nil {
+		t.Fatalf("OpenFilePageStore: %v", err)
+	}
+	tr, err := NewPersistentBTree(store)
+	if err != nil {
+		t.Fatalf("NewPersistentBTree: %v", err)
+	}
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		key := []byte(fmt.Sprintf("k%04d", i))
+		if err := tr.Insert(key, key); err != nil {
+			t.Fatalf("Insert(%s): %v", key, err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := OpenFilePageStore(path)
+	if err != nil {
+		t.Fatalf("reopen OpenFilePageStore: %v", err)
+	}
+	defer reopened.Close()
+
+	tr2, err := NewPersistentBTree(reopened)
+	if err != nil {
+		t.Fatalf("reopen NewPersistentBTree: %v", err)
+	}
+
+	for i := 0; i < n; i++ {
+		key := []byte(fmt.Sprintf("k%04d", i))
+		val, err := tr2.Find(key)
+		if err != nil {
+			t.Fatalf("Find(%s) after reopen: %v", key, err)
+		}
+		if string(val) != string(key) {
+			t.Fatalf("Find(%s) after reopen = %s, want %s", key, val, key)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Options configures a SafeBTree.
+type Options struct {
+	// NoLocks disables internal locking. Instead, every mutating call clones
+	// the current tree (see BTree.Clone), mutates the clone, and publishes
+	// it with a single atomic pointer store, so Load always observes a
+	// complete tree, never a partially-mutated one. This still requires the
+	// caller to serialize writers itself: SafeBTree only guarantees that
+	// reads via Find or Load never race with a write, not that two
+	// concurrent writes are ordered.
+	NoLocks bool
+}
+
+// SafeBTree wraps BTree for safe use from multiple goroutines. By default
+// every method takes sync.RWMutex the way any other shared mutable value
+// would. With Options.NoLocks, it instead relies on BTree's copy-on-write
+// Clone and an atomically-swapped root, so readers that call Load pay no
+// locking cost at all while a write is in flight.
+//
+// Happens-before: a call to Find (or a read through a tree returned by
+// Load) observes every write whose atomic.StorePointer commit (under
+// NoLocks) or whose locked mutation (otherwise) happened before the
+// corresponding atomic.LoadPointer or lock acquisition in that read, per the
+// usual Go memory model guarantees for atomic operations and sync.RWMutex. A reader that already holds a *BTree from Load
+// will not see writes committed afterwards; call Load again for a fresher
+// snapshot.
+type SafeBTree struct {
+	opts Options
+	mu   sync.RWMutex
+	tree unsafe.Pointer // *BTree
+}
+
+// NewSafeBTree creates an empty, concurrency-safe B-tree.
+func NewSafeBTree(opts Options) *SafeBTree {
+	s := &SafeBTree{opts: opts}
+	atomic.StorePointer(&s.tree, unsafe.Pointer(NewBTree()))
+	return s
+}
+
+func (s *SafeBTree) current() *BTree {
+	return (*BTree)(atomic.LoadPointer(&s.tree))
+}
+
+// Load returns a snapshot of the tree that readers can use without taking
+// any lock, even while a writer is active. Under Options.NoLocks, Load
+// itself takes no lock; otherwise it briefly takes the write lock to clone
+// the live tree, which later writes mutate in place.
+func (s *SafeBTree) Load() *BTree {
+	if s.opts.NoLocks {
+		return s.current()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current().Clone()
+}
+
+// commit publishes next as the tree's new state by swapping it in
+// atomically. Only the NoLocks write paths call it; the locked paths mutate
+// the live tree in place under mu instead.
+func (s *SafeBTree) commit(next *BTree) {
+	atomic.StorePointer(&s.tree, unsafe.Pointer(next))
+}
+
+func (s *SafeBTree) Find(key []byte) ([]byte, error) {
+	if s.opts.NoLocks {
+		return s.current().Find(key)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current().Find(key)
+}
+
+func (s *SafeBTree) insert(key, val []byte) {
+	if s.opts.NoLocks {
+		next := s.current().Clone()
+		next.insert(key, val)
+		s.commit(next)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current().insert(key, val)
+}
+
+func (s *SafeBTree) Delete(key []byte) error {
+	if s.opts.NoLocks {
+		next := s.current().Clone()
+		if err := next.Delete(key); err != nil {
+			return err
+		}
+		s.commit(next)
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current().Delete(key)
+}
+
+// Ascend calls iter for every item with key >= pivot, in ascending order.
+func (s *SafeBTree) Ascend(pivot []byte, iter func(key, value []byte) bool) {
+	if s.opts.NoLocks {
+		s.current().Ascend(pivot, iter)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.current().Ascend(pivot, iter)
+}
+
+// Descend calls iter for every item with key <= pivot, in descending order.
+func (s *SafeBTree) Descend(pivot []byte, iter func(key, value []byte) bool) {
+	if s.opts.NoLocks {
+		s.current().Descend(pivot, iter)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.current().Descend(pivot, iter)
+}
+
+// AscendRange calls iter for every item with lo <= key < hi, in ascending
+// order.
+func (s *SafeBTree) AscendRange(lo, hi []byte, iter func(key, value []byte) bool) {
+	if s.opts.NoLocks {
+		s.current().AscendRange(lo, hi, iter)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.current().AscendRange(lo, hi, iter)
+}
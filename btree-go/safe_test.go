@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// testConcurrentFindInsert runs one writer inserting sequential keys
+// alongside several readers calling Find in a loop. It exists to be run
+// under -race: a reader observing a partially-mutated tree should trip the
+// race detector even though the assertions below are loose (a key may
+// legitimately not be there yet).
+func testConcurrentFindInsert(t *testing.T, s *SafeBTree) {
+	t.Helper()
+
+	const n = 300
+	const readers = 8
+
+	var wg sync.WaitGroup
+	wg.Add(1 + readers)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			key := []byte(fmt.Sprintf("k%04d", i))
+			s.insert(key, key)
+		}
+	}()
+
+	for r := 0; r < readers; r++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				key := []byte(fmt.Sprintf("k%04d", i))
+				if val, err := s.Find(key); err == nil && string(val) != string(key) {
+					t.Errorf("Find(%s) = %s", key, val)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		key := []byte(fmt.Sprintf("k%04d", i))
+		if val, err := s.Find(key); err != nil || string(val) != string(key) {
+			t.Fatalf("Find(%s) = %s, %v, want %s", key, val, err, key)
+		}
+	}
+}
+
+func TestSafeBTreeConcurrentFindInsertLocked(t *testing.T) {
+	testConcurrentFindInsert(t, NewSafeBTree(Options{}))
+}
+
+func TestSafeBTreeConcurrentFindInsertNoLocks(t *testing.T) {
+	testConcurrentFindInsert(t, NewSafeBTree(Options{NoLocks: true}))
+}
+
+// TestSafeBTreeLoadSnapshotStable checks that a *BTree returned by Load
+// never mutates underneath its caller, even while writes continue: Load's
+// whole contract is that it hands out an immutable snapshot.
+func TestSafeBTreeLoadSnapshotStable(t *testing.T) {
+	s := NewSafeBTree(Options{NoLocks: true})
+	s.insert([]byte("a"), []byte("1"))
+
+	snap := s.Load()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			key := []byte(fmt.Sprintf("k%04d", i))
+			s.insert(key, key)
+		}
+	}()
+	wg.Wait()
+
+	if val, err := snap.Find([]byte("a")); err != nil || string(val) != "1" {
+		t.Fatalf("snapshot changed: Find(a) = %s, %v", val, err)
+	}
+	if _, err := snap.Find([]byte("k0000")); err == nil {
+		t.Fatalf("snapshot observed a write that happened after Load")
+	}
+}